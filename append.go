@@ -0,0 +1,168 @@
+package archivex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// tarEndBlockSize is the size of the two zero blocks archive/tar's Writer.Close
+// writes to terminate an archive.
+const tarEndBlockSize = 2 * 512
+
+var errAppendCompressedTar = errors.New("archivex: cannot append to a compressed tar archive")
+
+// OpenForAppend opens an existing, uncompressed tar archive at name and positions the
+// writer so further AddFile/AddDirectory calls add entries after the last one, without
+// rewriting the file. Gzip-compressed archives are rejected; see AppendWriter.
+func (t *TarFile) OpenForAppend(name string) error {
+	file, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	if err := t.AppendWriter(file); err != nil {
+		file.Close()
+		return err
+	}
+	t.Name = name
+	t.out = file
+	return nil
+}
+
+// AppendWriter positions the writer to add further entries to an already-open,
+// uncompressed tar archive, by seeking back over the trailing zero blocks written by
+// a previous Close and resuming the tar.Writer from there. It returns
+// errAppendCompressedTar for a gzipped archive, since rewriting into the middle of a
+// gzip stream's dictionary is not safe.
+func (t *TarFile) AppendWriter(rw io.ReadWriteSeeker) error {
+	magic := make([]byte, 6)
+	n, err := io.ReadFull(rw, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	if DetectCompression(magic[:n]) != Uncompressed {
+		return errAppendCompressedTar
+	}
+
+	end, err := rw.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	offset := end - tarEndBlockSize
+	if offset < 0 {
+		offset = 0
+	}
+	if _, err := rw.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	t.Writer = tar.NewWriter(rw)
+	t.Compressed = false
+	return nil
+}
+
+// OpenForAppend opens an existing zip archive at name and positions the writer so
+// further AddFile/AddDirectory calls add entries after the last one, without
+// recompressing the entries already present.
+func (z *ZipFile) OpenForAppend(name string) error {
+	file, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	if err := z.AppendWriter(file); err != nil {
+		file.Close()
+		return err
+	}
+	z.Name = name
+	z.out = file
+	return nil
+}
+
+// AppendWriter positions the writer to add further entries to an already-open zip
+// archive backed by file. It locates the existing central directory, truncates the
+// file there, and re-emits every prior entry's raw (still-compressed) bytes into a new
+// zip.Writer via CreateRaw so nothing already written is decompressed and recompressed.
+func (z *ZipFile) AppendWriter(file *os.File) error {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	cdOffset, err := zipCentralDirectoryOffset(file, size)
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(io.NewSectionReader(file, 0, size), size)
+	if err != nil {
+		return err
+	}
+
+	if err := file.Truncate(cdOffset); err != nil {
+		return err
+	}
+	if _, err := file.Seek(cdOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	z.Writer = zip.NewWriter(file)
+	for _, f := range zr.File {
+		raw, err := f.OpenRaw()
+		if err != nil {
+			return err
+		}
+		w, err := z.Writer.CreateRaw(&f.FileHeader)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var zipEOCDSignature = []byte{0x50, 0x4B, 0x05, 0x06}
+
+// zipCentralDirectoryOffset scans the end-of-central-directory record of a zip file
+// (searching backward, since it's followed by a variable-length comment) and returns
+// the byte offset at which its central directory begins - the point after which it's
+// safe to truncate the file and start appending new entries.
+//
+// The EOCD's own "offset of start of central directory" field is relative to wherever
+// the zip.Writer that produced it believed position 0 to be, which is only the true
+// absolute file offset the first time an archive is written. After an append, the
+// previous round's zip.Writer started at a non-zero file position, so that field
+// under-reports the real offset by exactly that amount (archive/zip's reader corrects
+// for this the same way, via the baseOffset it derives in readDirectoryEnd). Since the
+// central directory always sits immediately before the EOCD record, computing the
+// start from the EOCD's own position and the (always-accurate) central directory size
+// sidesteps that relative/absolute confusion entirely.
+func zipCentralDirectoryOffset(r io.ReaderAt, size int64) (int64, error) {
+	const eocdMinSize = 22
+	const maxCommentSize = 1<<16 - 1
+
+	searchSize := int64(eocdMinSize + maxCommentSize)
+	if searchSize > size {
+		searchSize = size
+	}
+
+	buf := make([]byte, searchSize)
+	if _, err := r.ReadAt(buf, size-searchSize); err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	idx := bytes.LastIndex(buf, zipEOCDSignature)
+	if idx < 0 || int64(idx+eocdMinSize) > searchSize {
+		return 0, fmt.Errorf("archivex: could not locate zip central directory")
+	}
+
+	eocdPos := size - searchSize + int64(idx)
+	directorySize := binary.LittleEndian.Uint32(buf[idx+12 : idx+16])
+	return eocdPos - int64(directorySize), nil
+}
@@ -0,0 +1,185 @@
+package archivex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTarFileAppend(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.tar")
+
+	tf := &TarFile{}
+	if err := tf.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.AddFile("one.txt", bytes.NewReader([]byte("one"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tf2 := &TarFile{}
+	if err := tf2.OpenForAppend(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf2.AddFile("two.txt", bytes.NewReader([]byte("two"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf2.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTarEntries(t, name, map[string]string{"one.txt": "one", "two.txt": "two"})
+}
+
+func TestZipFileAppend(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.zip")
+
+	zf := &ZipFile{}
+	if err := zf.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.AddFile("one.txt", bytes.NewReader([]byte("one"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zf2 := &ZipFile{}
+	if err := zf2.OpenForAppend(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf2.AddFile("two.txt", bytes.NewReader([]byte("two"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf2.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertZipEntries(t, name, map[string]string{"one.txt": "one", "two.txt": "two"})
+}
+
+// TestZipFileAppendTwice covers a second append on the same archive, which previously
+// corrupted the file: OpenForAppend's central-directory lookup trusted the EOCD's
+// "offset of start of central directory" field as an absolute file offset, but after
+// one append that field is relative to the appending zip.Writer's own start, causing
+// the second round's Truncate to chop into the first round's live entry data.
+func TestZipFileAppendTwice(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.zip")
+
+	zf := &ZipFile{}
+	if err := zf.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.AddFile("one.txt", bytes.NewReader([]byte("one"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []struct{ name, content string }{
+		{"two.txt", "two"},
+		{"three.txt", "three"},
+	} {
+		zf := &ZipFile{}
+		if err := zf.OpenForAppend(name); err != nil {
+			t.Fatalf("OpenForAppend before adding %s: %v", entry.name, err)
+		}
+		if err := zf.AddFile(entry.name, bytes.NewReader([]byte(entry.content))); err != nil {
+			t.Fatal(err)
+		}
+		if err := zf.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertZipEntries(t, name, map[string]string{
+		"one.txt":   "one",
+		"two.txt":   "two",
+		"three.txt": "three",
+	})
+}
+
+func TestTarFileOpenForAppendRejectsCompressed(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.tar.gz")
+
+	tf := &TarFile{}
+	if err := tf.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.AddFile("one.txt", bytes.NewReader([]byte("one"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tf2 := &TarFile{}
+	if err := tf2.OpenForAppend(name); err != errAppendCompressedTar {
+		t.Fatalf("expected errAppendCompressedTar, got %v", err)
+	}
+}
+
+func assertTarEntries(t *testing.T, name string, want map[string]string) {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got := map[string]string{}
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[hdr.Name] = string(data)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Fatalf("entry %s: got %q, want %q (all entries: %v)", name, got[name], content, got)
+		}
+	}
+}
+
+func assertZipEntries(t *testing.T, name string, want map[string]string) {
+	t.Helper()
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	got := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[f.Name] = string(data)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Fatalf("entry %s: got %q, want %q (all entries: %v)", name, got[name], content, got)
+		}
+	}
+}
@@ -10,10 +10,15 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"fmt"
 	"io"
 	"os"
 	"strings"
 	"time"
+
+	bzip2w "github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
 // interface
@@ -26,7 +31,9 @@ type Archivex interface {
 }
 
 // ArchiveWriteFunc is the closure used by an archive's AddAll method to actually put a file into an archive
-// Note that for directory entries, this func will be called with a nil 'file' param
+// Note that for directory entries, this func will be called with a nil 'file' param. For a symlink entry in
+// a TarFile (zip has no native symlink type, so AddAll resolves those to the target's real content instead),
+// file is a Symlink carrying the link's target rather than an opened reader.
 type ArchiveWriteFunc func(info os.FileInfo, file io.Reader, entryName string) (err error)
 
 // ZipFile implement *zip.Writer
@@ -40,9 +47,10 @@ type ZipFile struct {
 type TarFile struct {
 	Writer     *tar.Writer
 	Name       string
-	GzWriter   *gzip.Writer
+	Options    TarFileOptions
 	Compressed bool
 	out        io.Writer
+	compressor io.WriteCloser
 }
 
 // Create new file zip
@@ -73,18 +81,7 @@ func (z *ZipFile) CreateWriter(name string, w io.Writer) error {
 
 // Add file reader in archive zip
 func (z *ZipFile) AddFile(path string, file io.ReadSeeker) error {
-	header := &zip.FileHeader{
-		Name:   path,
-		Flags:  1 << 11, // use utf8 encoding the file Name
-		Method: zip.Deflate,
-	}
-	zipWriter, err := z.Writer.CreateHeader(header)
-	if err != nil {
-		return err
-	}
-
-	_, err = io.CopyBuffer(zipWriter, file, make([]byte, 128*1024))
-	return err
+	return z.AddFileStream(path, 0, file)
 }
 
 func (z *ZipFile) AddDirectory(path string) error {
@@ -110,42 +107,53 @@ func (z *ZipFile) Close() error {
 	return err
 }
 
-func (t *TarFile) configureName(name string) {
-	// check the filename extension
-
-	// if it has a .gz, we'll compress it.
-	t.Compressed = strings.HasSuffix(name, ".tar.gz")
-
-	// check to see if they have the wrong extension
-	if !strings.HasSuffix(name, ".tar.gz") && !strings.HasSuffix(name, ".tar") {
-		// is it .zip? replace it
-		if strings.HasSuffix(name, ".zip") {
+// configureName sets t.Name and picks the codec. When t.Options hasn't
+// pinned an explicit Compression, it infers the codec from name's suffix,
+// adding or replacing the suffix if it isn't a known tar one. This is only a
+// fallback: setting t.Options.Compression before calling Create or
+// CreateWriter always wins, and name is then used exactly as given.
+func (t *TarFile) configureName(name string) Compression {
+	comp := t.Options.Compression
+	if comp == Auto {
+		switch {
+		case strings.HasSuffix(name, ".tar.gz"):
+			comp = Gzip
+		case strings.HasSuffix(name, ".tar.bz2"):
+			comp = Bzip2
+		case strings.HasSuffix(name, ".tar.xz"):
+			comp = Xz
+		case strings.HasSuffix(name, ".tar.zst"):
+			comp = Zstd
+		case strings.HasSuffix(name, ".tar"):
+			comp = Uncompressed
+		case strings.HasSuffix(name, ".zip"):
+			// wrong extension for a tar file, replace it
 			name = strings.Replace(name, ".zip", ".tar.gz", -1)
-			t.Compressed = true
-		} else {
-			// if it's not, add .tar
-			// since we'll assume it's not compressed
+			comp = Gzip
+		default:
+			// if it's not a known extension, add .tar and assume uncompressed
 			name = name + ".tar"
+			comp = Uncompressed
 		}
 	}
 
 	t.Name = name
+	t.Compressed = comp != Uncompressed
+	return comp
 }
 
 // Create new Tar file
 func (t *TarFile) Create(name string) error {
-	t.configureName(name)
+	comp := t.configureName(name)
 
-	file, err := os.Create(name)
+	file, err := os.Create(t.Name)
 	if err != nil {
 		return err
 	}
 
-	if t.Compressed {
-		t.GzWriter = gzip.NewWriter(file)
-		t.Writer = tar.NewWriter(t.GzWriter)
-	} else {
-		t.Writer = tar.NewWriter(file)
+	if err := t.initWriter(comp, file); err != nil {
+		file.Close()
+		return err
 	}
 	t.out = file
 	return nil
@@ -153,18 +161,51 @@ func (t *TarFile) Create(name string) error {
 
 // Create a new Tar and write it to a given writer
 func (t *TarFile) CreateWriter(name string, w io.Writer) error {
-	t.configureName(name)
+	comp := t.configureName(name)
 
-	if t.Compressed {
-		t.GzWriter = gzip.NewWriter(w)
-		t.Writer = tar.NewWriter(t.GzWriter)
-	} else {
-		t.Writer = tar.NewWriter(w)
+	if err := t.initWriter(comp, w); err != nil {
+		return err
 	}
 	t.out = w
 	return nil
 }
 
+// initWriter wraps w in the codec for comp and sets up t.Writer.
+func (t *TarFile) initWriter(comp Compression, w io.Writer) error {
+	switch comp {
+	case Uncompressed, Auto:
+		t.Writer = tar.NewWriter(w)
+	case Gzip:
+		gw := gzip.NewWriter(w)
+		t.compressor = gw
+		t.Writer = tar.NewWriter(gw)
+	case Bzip2:
+		bw, err := bzip2w.NewWriter(w, nil)
+		if err != nil {
+			return err
+		}
+		t.compressor = bw
+		t.Writer = tar.NewWriter(bw)
+	case Xz:
+		xw, err := xz.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		t.compressor = xw
+		t.Writer = tar.NewWriter(xw)
+	case Zstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		t.compressor = zw
+		t.Writer = tar.NewWriter(zw)
+	default:
+		return fmt.Errorf("archivex: unknown compression %d", comp)
+	}
+	return nil
+}
+
 // Add add byte in archive tar
 func (t *TarFile) AddFile(path string, file io.ReadSeeker) error {
 	// Seek to the end to find the file size
@@ -179,19 +220,7 @@ func (t *TarFile) AddFile(path string, file io.ReadSeeker) error {
 		return err
 	}
 
-	header := &tar.Header{
-		Name:     path,
-		Typeflag: tar.TypeReg,
-		Size:     size,
-		Mode:     0666,
-		ModTime:  time.Now(),
-	}
-	err = t.Writer.WriteHeader(header)
-	if err != nil {
-		return err
-	}
-	_, err = io.Copy(t.Writer, file)
-	return err
+	return t.AddFileStream(path, size, file)
 }
 
 func (t *TarFile) AddDirectory(path string) error {
@@ -216,8 +245,8 @@ func (t *TarFile) Close() error {
 		return err
 	}
 
-	if t.Compressed {
-		err = t.GzWriter.Close()
+	if t.compressor != nil {
+		err = t.compressor.Close()
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,220 @@
+package archivex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compression identifies the codec wrapping a tar stream. The zero value,
+// Auto, tells Create/CreateWriter to fall back to inferring the codec from
+// the archive's file extension.
+type Compression int
+
+const (
+	Auto Compression = iota
+	Uncompressed
+	Gzip
+	Bzip2
+	Xz
+	Zstd
+)
+
+// TarFileOptions configures the codec used by Create/CreateWriter.
+// The zero value, Auto, leaves the choice to configureName's extension-based
+// inference: a ".tar.gz" name selects Gzip, an unrecognized extension falls
+// back to Uncompressed. Set Compression explicitly to bypass that inference.
+type TarFileOptions struct {
+	Compression Compression
+}
+
+var (
+	gzipMagic  = []byte{0x1F, 0x8B}
+	bzip2Magic = []byte{0x42, 0x5A, 0x68}
+	xzMagic    = []byte{0xFD, 0x37, 0x7A, 0x58, 0x5A, 0x00}
+	zstdMagic  = []byte{0x28, 0xB5, 0x2F, 0xFD}
+	zipMagic   = []byte{0x50, 0x4B, 0x03, 0x04}
+)
+
+// DetectCompression sniffs the leading bytes of a stream and reports the
+// codec it was written with. It returns Uncompressed if none of the known
+// magic numbers match (this also covers a bare, uncompressed tar and a zip
+// archive, since neither is wrapped in a stream codec).
+func DetectCompression(b []byte) Compression {
+	switch {
+	case bytes.HasPrefix(b, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(b, bzip2Magic):
+		return Bzip2
+	case bytes.HasPrefix(b, xzMagic):
+		return Xz
+	case bytes.HasPrefix(b, zstdMagic):
+		return Zstd
+	default:
+		return Uncompressed
+	}
+}
+
+// decompressReader wraps r in the reader for the given Compression.
+func decompressReader(c Compression, r io.Reader) (io.Reader, error) {
+	switch c {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Bzip2:
+		return bzip2.NewReader(r), nil
+	case Xz:
+		return xz.NewReader(r)
+	case Zstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case Uncompressed:
+		return r, nil
+	default:
+		return nil, fmt.Errorf("archivex: unknown compression %d", c)
+	}
+}
+
+// Header is the normalized entry metadata yielded while iterating an
+// archive opened with Open or OpenReader, regardless of whether the
+// underlying format is tar or zip.
+type Header struct {
+	Name     string
+	Size     int64
+	Mode     int64
+	Typeflag byte
+}
+
+// Reader iterates the entries of an archive that was opened with Open or
+// OpenReader, auto-detecting the compression codec (and, for zip, the
+// container format) used to write it.
+type Reader struct {
+	tr     *tar.Reader
+	zr     *zip.Reader
+	zFiles []*zip.File
+	zIndex int
+	zOpen  io.ReadCloser
+
+	closer io.Closer
+}
+
+// Open opens name on disk and returns a Reader that auto-detects its
+// compression and container format.
+func Open(name string) (*Reader, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(file, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// OpenReader wraps an already-open stream and returns a Reader that
+// auto-detects its compression and container format. If r implements
+// io.Closer, it is closed when the returned Reader is closed.
+func OpenReader(r io.Reader) (*Reader, error) {
+	closer, _ := r.(io.Closer)
+	return newReader(r, closer)
+}
+
+func newReader(r io.Reader, closer io.Closer) (*Reader, error) {
+	buf := make([]byte, 6)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	buf = buf[:n]
+	lead := io.MultiReader(bytes.NewReader(buf), r)
+
+	if bytes.HasPrefix(buf, zipMagic) {
+		data, err := ioutil.ReadAll(lead)
+		if err != nil {
+			return nil, err
+		}
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		return &Reader{zr: zr, zFiles: zr.File, closer: closer}, nil
+	}
+
+	comp := DetectCompression(buf)
+	decompressed, err := decompressReader(comp, lead)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{tr: tar.NewReader(decompressed), closer: closer}, nil
+}
+
+// Next advances to the next entry in the archive and returns its header.
+// It returns io.EOF when there are no more entries.
+func (r *Reader) Next() (*Header, error) {
+	if r.tr != nil {
+		hdr, err := r.tr.Next()
+		if err != nil {
+			return nil, err
+		}
+		return &Header{Name: hdr.Name, Size: hdr.Size, Mode: hdr.Mode, Typeflag: hdr.Typeflag}, nil
+	}
+
+	if r.zOpen != nil {
+		r.zOpen.Close()
+		r.zOpen = nil
+	}
+	if r.zIndex >= len(r.zFiles) {
+		return nil, io.EOF
+	}
+	f := r.zFiles[r.zIndex]
+	r.zIndex++
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	r.zOpen = rc
+
+	typeflag := byte(tar.TypeReg)
+	if f.FileInfo().IsDir() {
+		typeflag = tar.TypeDir
+	}
+	return &Header{Name: f.Name, Size: int64(f.UncompressedSize64), Mode: int64(f.Mode()), Typeflag: typeflag}, nil
+}
+
+// Read reads from the current entry's content, as selected by the most
+// recent call to Next.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.tr != nil {
+		return r.tr.Read(p)
+	}
+	if r.zOpen == nil {
+		return 0, io.EOF
+	}
+	return r.zOpen.Read(p)
+}
+
+// Close releases the resources backing the archive, closing the
+// underlying stream if Open or OpenReader took ownership of it.
+func (r *Reader) Close() error {
+	if r.zOpen != nil {
+		r.zOpen.Close()
+	}
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package archivex
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		comp Compression
+	}{
+		{"out.tar", Uncompressed},
+		{"out.tar.gz", Gzip},
+		{"out.tar.bz2", Bzip2},
+		{"out.tar.xz", Xz},
+		{"out.tar.zst", Zstd},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), c.name)
+
+			tf := &TarFile{Options: TarFileOptions{Compression: c.comp}}
+			if err := tf.Create(name); err != nil {
+				t.Fatal(err)
+			}
+			if err := tf.AddFile("hello.txt", bytes.NewReader([]byte("hello world"))); err != nil {
+				t.Fatal(err)
+			}
+			if err := tf.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			raw, err := ioutil.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := DetectCompression(raw); got != c.comp {
+				t.Fatalf("DetectCompression: got %v, want %v", got, c.comp)
+			}
+
+			r, err := Open(name)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer r.Close()
+
+			hdr, err := r.Next()
+			if err != nil {
+				t.Fatalf("Next: %v", err)
+			}
+			if hdr.Name != "hello.txt" {
+				t.Fatalf("entry name: got %q, want hello.txt", hdr.Name)
+			}
+			data, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "hello world" {
+				t.Fatalf("entry content: got %q, want %q", data, "hello world")
+			}
+			if _, err := r.Next(); err != io.EOF {
+				t.Fatalf("expected io.EOF after the only entry, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenZip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out.zip")
+
+	zf := &ZipFile{}
+	if err := zf.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.AddFile("hello.txt", bytes.NewReader([]byte("hello world"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(name)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+
+	hdr, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if hdr.Name != "hello.txt" {
+		t.Fatalf("entry name: got %q, want hello.txt", hdr.Name)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Fatalf("entry content: got %q, want %q", data, "hello world")
+	}
+}
@@ -0,0 +1,94 @@
+package archivex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"os"
+)
+
+// AddFileFromPath adds the file at fsPath to the archive under archivePath, using
+// tar.FileInfoHeader to populate its mode, modtime, and (on platforms that support it)
+// uid/gid, instead of the fixed 0666/time.Now() AddFile writes. Symlinks are recorded
+// as TypeSymlink entries with their target in Linkname, rather than followed.
+func (t *TarFile) AddFileFromPath(archivePath, fsPath string) error {
+	info, err := os.Lstat(fsPath)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(fsPath)
+		if err != nil {
+			return err
+		}
+		return t.addFileHeader(archivePath, info, link, nil)
+	}
+
+	file, err := os.Open(fsPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return t.addFileHeader(archivePath, info, "", file)
+}
+
+// AddFileWithInfo adds file to the archive under archivePath, using info (typically
+// from os.Stat) to populate the entry's mode and modtime, for callers with in-memory
+// or otherwise already-open data who still want accurate metadata in the archive.
+func (t *TarFile) AddFileWithInfo(archivePath string, file io.ReadSeeker, info os.FileInfo) error {
+	return t.addFileHeader(archivePath, info, "", file)
+}
+
+func (t *TarFile) addFileHeader(archivePath string, info os.FileInfo, link string, file io.Reader) error {
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = archivePath
+
+	if err := t.Writer.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if file == nil {
+		return nil
+	}
+	_, err = io.Copy(t.Writer, file)
+	return err
+}
+
+// AddFileFromPath adds the file at fsPath to the archive under archivePath, using
+// zip.FileInfoHeader to populate its mode and modtime, which AddFile otherwise leaves
+// unset.
+func (z *ZipFile) AddFileFromPath(archivePath, fsPath string) error {
+	info, err := os.Stat(fsPath)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(fsPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return z.AddFileWithInfo(archivePath, file, info)
+}
+
+// AddFileWithInfo adds file to the archive under archivePath, using info (typically
+// from os.Stat) to populate the entry's mode and modtime, for callers with in-memory
+// or otherwise already-open data who still want accurate metadata in the archive.
+func (z *ZipFile) AddFileWithInfo(archivePath string, file io.ReadSeeker, info os.FileInfo) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = archivePath
+	hdr.Flags = 1 << 11 // use utf8 encoding the file Name
+	hdr.Method = zip.Deflate
+
+	w, err := z.Writer.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyBuffer(w, file, make([]byte, 128*1024))
+	return err
+}
@@ -0,0 +1,116 @@
+package archivex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTarFileAddFileFromPathPreservesMetadata(t *testing.T) {
+	dir := t.TempDir()
+	fsPath := filepath.Join(dir, "source.txt")
+	if err := os.WriteFile(fsPath, []byte("contents"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2011, 3, 4, 5, 6, 7, 0, time.UTC)
+	if err := os.Chtimes(fsPath, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "out.tar")
+	tf := &TarFile{}
+	if err := tf.Create(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.AddFileFromPath("source.txt", fsPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hdr.Mode&0777 != 0640 {
+		t.Fatalf("mode: got %o, want %o", hdr.Mode&0777, 0640)
+	}
+	if !hdr.ModTime.Equal(mtime) {
+		t.Fatalf("modtime: got %v, want %v", hdr.ModTime, mtime)
+	}
+	if string(data) != "contents" {
+		t.Fatalf("content: got %q, want %q", data, "contents")
+	}
+}
+
+func TestZipFileAddFileFromPathPreservesMetadata(t *testing.T) {
+	dir := t.TempDir()
+	fsPath := filepath.Join(dir, "source.txt")
+	if err := os.WriteFile(fsPath, []byte("contents"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2011, 3, 4, 5, 6, 7, 0, time.UTC)
+	if err := os.Chtimes(fsPath, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "out.zip")
+	zf := &ZipFile{}
+	if err := zf.Create(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.AddFileFromPath("source.txt", fsPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	if len(zr.File) != 1 {
+		t.Fatalf("expected exactly one entry, got %d", len(zr.File))
+	}
+	zf2 := zr.File[0]
+
+	rc, err := zf2.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if zf2.Mode()&0777 != 0640 {
+		t.Fatalf("mode: got %o, want %o", zf2.Mode()&0777, 0640)
+	}
+	if !zf2.Modified.Equal(mtime) {
+		t.Fatalf("modtime: got %v, want %v", zf2.Modified, mtime)
+	}
+	if string(data) != "contents" {
+		t.Fatalf("content: got %q, want %q", data, "contents")
+	}
+}
@@ -0,0 +1,118 @@
+package archivex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// BufferedFileThreshold is the largest amount of data AddFileBuffered will hold in
+// memory; once that much has been read, the buffered bytes and the rest of the
+// content are all copied to a temp file instead, so it can still discover the
+// content's total size without requiring an io.ReadSeeker from the caller. It may be
+// changed before calling AddFileBuffered.
+var BufferedFileThreshold int64 = 8 * 1024 * 1024
+
+// AddFileStream writes a tar header using the caller-supplied size and streams r's
+// content directly into the archive. This avoids the seek-to-end-and-back AddFile uses
+// to discover a file's size, for sources (HTTP bodies, pipes) that can't seek at all.
+func (t *TarFile) AddFileStream(path string, size int64, r io.Reader) error {
+	header := &tar.Header{
+		Name:     path,
+		Typeflag: tar.TypeReg,
+		Size:     size,
+		Mode:     0666,
+		ModTime:  time.Now(),
+	}
+	if err := t.Writer.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(t.Writer, r)
+	return err
+}
+
+// AddFileBuffered buffers r - in memory up to BufferedFileThreshold, spilling to a
+// temp file beyond that - so its size can be discovered and written with
+// AddFileStream, for callers that only have a plain io.Reader.
+func (t *TarFile) AddFileBuffered(path string, r io.Reader) error {
+	buffered, size, cleanup, err := bufferReader(r)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return t.AddFileStream(path, size, buffered)
+}
+
+// AddFileStream writes a zip entry and streams r's content directly into the archive,
+// without requiring an io.ReadSeeker. size is accepted for symmetry with TarFile's
+// AddFileStream, but the zip format doesn't need it upfront: zip.Writer records the
+// entry's length in a trailing data descriptor.
+func (z *ZipFile) AddFileStream(path string, size int64, r io.Reader) error {
+	header := &zip.FileHeader{
+		Name:   path,
+		Flags:  1 << 11, // use utf8 encoding the file Name
+		Method: zip.Deflate,
+	}
+	zipWriter, err := z.Writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyBuffer(zipWriter, r, make([]byte, 128*1024))
+	return err
+}
+
+// AddFileBuffered buffers r - in memory up to BufferedFileThreshold, spilling to a
+// temp file beyond that - before writing it to the archive, for callers that only
+// have a plain io.Reader.
+func (z *ZipFile) AddFileBuffered(path string, r io.Reader) error {
+	buffered, size, cleanup, err := bufferReader(r)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return z.AddFileStream(path, size, buffered)
+}
+
+// bufferReader consumes r fully into memory, or into a temp file once
+// BufferedFileThreshold is exceeded, and returns a seekable view of it, its total
+// size, and a cleanup func the caller must run once done reading.
+func bufferReader(r io.Reader) (_ io.Reader, size int64, cleanup func(), err error) {
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, r, BufferedFileThreshold)
+	if err == io.EOF {
+		return bytes.NewReader(buf.Bytes()), n, func() {}, nil
+	}
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	tmp, err := ioutil.TempFile("", "archivex-buffered-")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	cleanup = func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	if _, err := io.Copy(tmp, &buf); err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	written, err := io.Copy(tmp, r)
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	return tmp, n + written, cleanup, nil
+}
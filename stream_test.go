@@ -0,0 +1,133 @@
+package archivex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTarFileAddFileBufferedSpillsToTempFile(t *testing.T) {
+	orig := BufferedFileThreshold
+	BufferedFileThreshold = 4
+	defer func() { BufferedFileThreshold = orig }()
+
+	content := []byte("this is longer than the lowered threshold")
+	out := filepath.Join(t.TempDir(), "out.tar")
+
+	tf := &TarFile{}
+	if err := tf.Create(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.AddFileBuffered("spilled.txt", bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Size != int64(len(content)) {
+		t.Fatalf("header size: got %d, want %d", hdr.Size, len(content))
+	}
+	data, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatalf("content: got %q, want %q", data, content)
+	}
+}
+
+func TestZipFileAddFileBufferedSpillsToTempFile(t *testing.T) {
+	orig := BufferedFileThreshold
+	BufferedFileThreshold = 4
+	defer func() { BufferedFileThreshold = orig }()
+
+	content := []byte("this is longer than the lowered threshold")
+	out := filepath.Join(t.TempDir(), "out.zip")
+
+	zf := &ZipFile{}
+	if err := zf.Create(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.AddFileBuffered("spilled.txt", bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	if len(zr.File) != 1 {
+		t.Fatalf("expected exactly one entry, got %d", len(zr.File))
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatalf("content: got %q, want %q", data, content)
+	}
+}
+
+func TestTarFileAddFileStreamWithExplicitSize(t *testing.T) {
+	content := []byte("stream me")
+	out := filepath.Join(t.TempDir(), "out.tar")
+
+	tf := &TarFile{}
+	if err := tf.Create(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.AddFileStream("streamed.txt", int64(len(content)), bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Size != int64(len(content)) {
+		t.Fatalf("header size: got %d, want %d", hdr.Size, len(content))
+	}
+	data, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatalf("content: got %q, want %q", data, content)
+	}
+}
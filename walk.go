@@ -0,0 +1,203 @@
+package archivex
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RenameAdapter is a chain of entry transforms applied, in order, to every
+// entry added via AddAll. Each link receives the Header produced by the
+// previous one; a link that returns nil drops the entry from the archive.
+// This makes renaming or filtering a subtree (e.g. rewriting "old/foo" to
+// "new/foo") a matter of composing small functions rather than rebuilding
+// the archive by hand.
+type RenameAdapter []func(hdr *Header) *Header
+
+// Apply runs hdr through the chain, returning nil as soon as any link does.
+func (a RenameAdapter) Apply(hdr *Header) *Header {
+	for _, fn := range a {
+		if hdr == nil {
+			return nil
+		}
+		hdr = fn(hdr)
+	}
+	return hdr
+}
+
+// NewRenameFunc returns an ArchiveWriteFunc that runs each entry's name
+// through chain before handing it to add. Entries dropped by chain are
+// skipped entirely.
+func NewRenameFunc(chain RenameAdapter, add func(name string, info os.FileInfo, file io.Reader) error) ArchiveWriteFunc {
+	return func(info os.FileInfo, file io.Reader, entryName string) error {
+		hdr := &Header{Name: entryName, Mode: int64(info.Mode())}
+		switch {
+		case info.IsDir():
+			hdr.Typeflag = tar.TypeDir
+		case file != nil:
+			if _, ok := file.(Symlink); ok {
+				hdr.Typeflag = tar.TypeSymlink
+			}
+		}
+		hdr = chain.Apply(hdr)
+		if hdr == nil {
+			return nil
+		}
+		return add(hdr.Name, info, file)
+	}
+}
+
+// Symlink is the io.Reader AddAll passes to fn in place of an opened file
+// when the walked entry is a symlink that the archive format can record
+// natively (currently: tar). It never has content of its own to read -
+// Target holds the link's destination from os.Readlink, which fn needs to
+// reconstruct the entry after the RenameAdapter chain has had a chance to
+// rename or drop it.
+type Symlink struct {
+	Target string
+}
+
+// Read always reports io.EOF; Symlink exists to carry Target, not bytes.
+func (Symlink) Read([]byte) (int, error) { return 0, io.EOF }
+
+// entryName computes the archive-relative name for fsPath found while
+// walking root, normalizing path separators to '/'. When includeRoot is
+// true, the walked directory's own base name is kept as a leading path
+// component; otherwise entries are relative to root's contents.
+func entryName(root, fsPath string, includeRoot bool) (string, error) {
+	rel, err := filepath.Rel(root, fsPath)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		rel = ""
+	}
+	if includeRoot {
+		base := filepath.ToSlash(filepath.Base(filepath.Clean(root)))
+		if rel == "" {
+			rel = base
+		} else {
+			rel = base + "/" + rel
+		}
+	}
+	return rel, nil
+}
+
+// AddAll walks root and adds every file and directory beneath it to the
+// archive. If includeRoot is true, entries are prefixed with root's own
+// base name; otherwise they're relative to root's contents. fn is called
+// once per entry (with a nil file for directories) so callers can rename,
+// skip, or rewrite content; pass nil to add each entry with
+// AddFileWithInfo/AddDirectory, keeping its mode and modtime.
+func (z *ZipFile) AddAll(root string, includeRoot bool, fn ArchiveWriteFunc) error {
+	if fn == nil {
+		fn = func(info os.FileInfo, file io.Reader, entryName string) error {
+			if info.IsDir() {
+				return z.AddDirectory(entryName)
+			}
+			return z.AddFileWithInfo(entryName, file.(io.ReadSeeker), info)
+		}
+	}
+
+	return filepath.Walk(root, func(fsPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := entryName(root, fsPath, includeRoot)
+		if err != nil || name == "" {
+			return err
+		}
+
+		if info.IsDir() {
+			return fn(info, nil, name)
+		}
+
+		// filepath.Walk reports symlinks via their own (Lstat-based) info rather
+		// than the target's, and zip has no symlink entry type of its own. Resolve
+		// the link ourselves: a link to a directory is left out entirely (AddAll,
+		// like filepath.Walk, never descends into symlinked directories), and a
+		// link to a file is opened (which follows it) and passed through fn under
+		// the target's real info, same as any other file entry, so the rename
+		// chain sees it too.
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Stat(fsPath)
+			if err != nil {
+				return err
+			}
+			if target.IsDir() {
+				return nil
+			}
+			file, err := os.Open(fsPath)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return fn(target, file, name)
+		}
+
+		file, err := os.Open(fsPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return fn(info, file, name)
+	})
+}
+
+// AddAll walks root and adds every file and directory beneath it to the
+// archive. If includeRoot is true, entries are prefixed with root's own
+// base name; otherwise they're relative to root's contents. fn is called
+// once per entry (with a nil file for directories) so callers can rename,
+// skip, or rewrite content; pass nil to add each entry with
+// AddFileWithInfo/AddDirectory, keeping its mode and modtime, and to record
+// symlinks as TypeSymlink entries.
+func (t *TarFile) AddAll(root string, includeRoot bool, fn ArchiveWriteFunc) error {
+	if fn == nil {
+		fn = func(info os.FileInfo, file io.Reader, entryName string) error {
+			if info.IsDir() {
+				return t.AddDirectory(entryName)
+			}
+			if link, ok := file.(Symlink); ok {
+				return t.addFileHeader(entryName, info, link.Target, nil)
+			}
+			return t.AddFileWithInfo(entryName, file.(io.ReadSeeker), info)
+		}
+	}
+
+	return filepath.Walk(root, func(fsPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := entryName(root, fsPath, includeRoot)
+		if err != nil || name == "" {
+			return err
+		}
+
+		if info.IsDir() {
+			return fn(info, nil, name)
+		}
+
+		// filepath.Walk reports symlinks via their own (Lstat-based) info, which
+		// is exactly what a tar TypeSymlink entry needs (unlike zip, tar records
+		// symlinks natively rather than dereferencing them). Since there's no
+		// file content to stream, fn is called with a Symlink carrying the link's
+		// target from os.Readlink in place of an opened file, same as the nil
+		// file passed for directories, so the rename chain still sees the entry.
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(fsPath)
+			if err != nil {
+				return err
+			}
+			return fn(info, Symlink{Target: link}, name)
+		}
+
+		file, err := os.Open(fsPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return fn(info, file, name)
+	})
+}
@@ -0,0 +1,287 @@
+package archivex
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeTestTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("aaa"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("bbbb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "a.txt"), filepath.Join(root, "link-to-file.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "sub"), filepath.Join(root, "link-to-dir")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestTarFileAddAllWithSymlinks(t *testing.T) {
+	root := writeTestTree(t)
+	out := filepath.Join(t.TempDir(), "out.tar")
+
+	tf := &TarFile{}
+	if err := tf.Create(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.AddAll(root, false, nil); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	if err := tf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var names []string
+	var sawSymlink bool
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+		if hdr.Name == "link-to-file.txt" {
+			sawSymlink = true
+			if hdr.Typeflag != tar.TypeSymlink {
+				t.Fatalf("expected link-to-file.txt to be a symlink entry, got typeflag %v", hdr.Typeflag)
+			}
+			if hdr.Linkname != filepath.Join(root, "a.txt") {
+				t.Fatalf("unexpected link target: %s", hdr.Linkname)
+			}
+		}
+	}
+	if !sawSymlink {
+		t.Fatal("expected to see link-to-file.txt in the archive")
+	}
+
+	sort.Strings(names)
+	if !containsAll(names, "a.txt", "sub/b.txt") {
+		t.Fatalf("missing expected entries, got: %v", names)
+	}
+}
+
+func TestZipFileAddAllWithSymlinks(t *testing.T) {
+	root := writeTestTree(t)
+	out := filepath.Join(t.TempDir(), "out.zip")
+
+	zf := &ZipFile{}
+	if err := zf.Create(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := zf.AddAll(root, false, nil); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	if err := zf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == "link-to-file.txt" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "aaa" {
+				t.Fatalf("expected link-to-file.txt to contain the target's bytes, got %q", data)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	if !containsAll(names, "a.txt", "sub/b.txt", "link-to-file.txt") {
+		t.Fatalf("missing expected entries, got: %v", names)
+	}
+	for _, name := range names {
+		if name == "link-to-dir" {
+			t.Fatal("did not expect a symlinked directory to be added")
+		}
+	}
+}
+
+// renamePrefix returns a RenameAdapter that prefixes every entry's name.
+func renamePrefix(prefix string) RenameAdapter {
+	return RenameAdapter{
+		func(hdr *Header) *Header {
+			hdr.Name = prefix + hdr.Name
+			return hdr
+		},
+	}
+}
+
+func TestTarFileAddAllWithRenameAdapter(t *testing.T) {
+	root := writeTestTree(t)
+	out := filepath.Join(t.TempDir(), "out.tar")
+
+	tf := &TarFile{}
+	if err := tf.Create(out); err != nil {
+		t.Fatal(err)
+	}
+	fn := NewRenameFunc(renamePrefix("renamed/"), func(name string, info os.FileInfo, file io.Reader) error {
+		if info.IsDir() {
+			return tf.AddDirectory(name)
+		}
+		if link, ok := file.(Symlink); ok {
+			return tf.addFileHeader(name, info, link.Target, nil)
+		}
+		return tf.AddFileWithInfo(name, file.(io.ReadSeeker), info)
+	})
+	if err := tf.AddAll(root, false, fn); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	if err := tf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var names []string
+	var sawSymlink bool
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+		if hdr.Name == "renamed/link-to-file.txt" {
+			sawSymlink = true
+			if hdr.Typeflag != tar.TypeSymlink {
+				t.Fatalf("expected renamed/link-to-file.txt to be a symlink entry, got typeflag %v", hdr.Typeflag)
+			}
+			if hdr.Linkname != filepath.Join(root, "a.txt") {
+				t.Fatalf("unexpected link target: %s", hdr.Linkname)
+			}
+		}
+	}
+	if !sawSymlink {
+		t.Fatal("expected to see renamed/link-to-file.txt in the archive - the rename chain should see symlinks too")
+	}
+
+	sort.Strings(names)
+	if !containsAll(names, "renamed/a.txt", "renamed/sub/b.txt") {
+		t.Fatalf("missing expected renamed entries, got: %v", names)
+	}
+	for _, name := range names {
+		if name == "a.txt" || name == "link-to-file.txt" {
+			t.Fatalf("found un-renamed entry %q, rename chain was not applied", name)
+		}
+	}
+}
+
+func TestZipFileAddAllWithRenameAdapter(t *testing.T) {
+	root := writeTestTree(t)
+	out := filepath.Join(t.TempDir(), "out.zip")
+
+	zf := &ZipFile{}
+	if err := zf.Create(out); err != nil {
+		t.Fatal(err)
+	}
+	fn := NewRenameFunc(renamePrefix("renamed/"), func(name string, info os.FileInfo, file io.Reader) error {
+		if info.IsDir() {
+			return zf.AddDirectory(name)
+		}
+		return zf.AddFileWithInfo(name, file.(io.ReadSeeker), info)
+	})
+	if err := zf.AddAll(root, false, fn); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	if err := zf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	var names []string
+	var sawSymlink bool
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == "renamed/link-to-file.txt" {
+			sawSymlink = true
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "aaa" {
+				t.Fatalf("expected renamed/link-to-file.txt to contain the target's bytes, got %q", data)
+			}
+		}
+	}
+	if !sawSymlink {
+		t.Fatal("expected to see renamed/link-to-file.txt in the archive - the rename chain should see symlinks too")
+	}
+
+	sort.Strings(names)
+	if !containsAll(names, "renamed/a.txt", "renamed/sub/b.txt") {
+		t.Fatalf("missing expected renamed entries, got: %v", names)
+	}
+	for _, name := range names {
+		if name == "a.txt" || name == "link-to-file.txt" {
+			t.Fatalf("found un-renamed entry %q, rename chain was not applied", name)
+		}
+	}
+}
+
+func containsAll(haystack []string, want ...string) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range haystack {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}